@@ -0,0 +1,125 @@
+// Package ast defines the data types used to describe SQLVM column and
+// operand values, and the helpers used to pack/unpack them to and from their
+// on-chain byte representation.
+package ast
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/dexon-foundation/decimal"
+)
+
+// DataTypeMajor is the major class of a DataType, e.g. uint, int, bool.
+type DataTypeMajor uint8
+
+// DataTypeMinor further refines a DataTypeMajor, e.g. the byte width of an
+// integer or the number of decimal places of a fixed point number.
+type DataTypeMinor uint8
+
+// DataType packs a DataTypeMajor (high byte) and a DataTypeMinor (low byte)
+// into a single 16-bit value so it can be stored inline in column
+// descriptors and operand metadata.
+type DataType uint16
+
+// Supported DataTypeMajor values.
+const (
+	DataTypeMajorUint DataTypeMajor = iota
+	DataTypeMajorInt
+	DataTypeMajorBool
+	DataTypeMajorAddress
+	DataTypeMajorFixedBytes
+	DataTypeMajorDynamicBytes
+)
+
+// ComposeDataType packs major and minor into a single DataType value.
+func ComposeDataType(major DataTypeMajor, minor DataTypeMinor) DataType {
+	return DataType(uint16(major)<<8 | uint16(minor))
+}
+
+// DecomposeDataType splits a DataType back into its major and minor parts.
+func DecomposeDataType(dt DataType) (DataTypeMajor, DataTypeMinor) {
+	return DataTypeMajor(dt >> 8), DataTypeMinor(dt & 0xff)
+}
+
+// Size returns the number of bytes this data type occupies within a 32-byte
+// slot. It never exceeds 32.
+func (dt DataType) Size() uint8 {
+	major, minor := DecomposeDataType(dt)
+	switch major {
+	case DataTypeMajorUint, DataTypeMajorInt, DataTypeMajorFixedBytes:
+		return uint8(minor) + 1
+	case DataTypeMajorBool:
+		return 1
+	case DataTypeMajorAddress:
+		return 20
+	case DataTypeMajorDynamicBytes:
+		return 32
+	default:
+		return 0
+	}
+}
+
+// GetMinMax returns the minimum and maximum representable values of a
+// numeric data type. It is used by auto-increment sequences to detect
+// overflow before it happens.
+func (dt DataType) GetMinMax() (min, max decimal.Decimal, err error) {
+	major, minor := DecomposeDataType(dt)
+	bits := uint((uint(minor) + 1) * 8)
+	switch major {
+	case DataTypeMajorUint:
+		max = decimal.NewFromBigInt(new(big.Int).Sub(pow2(bits), big.NewInt(1)), 0)
+		min = decimal.Zero
+	case DataTypeMajorInt:
+		half := pow2(bits - 1)
+		max = decimal.NewFromBigInt(new(big.Int).Sub(half, big.NewInt(1)), 0)
+		min = decimal.NewFromBigInt(new(big.Int).Neg(half), 0)
+	default:
+		err = fmt.Errorf("ast: data type %v has no min/max", dt)
+	}
+	return
+}
+
+// pow2 returns 2^n as a big.Int.
+func pow2(n uint) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), n)
+}
+
+// DecimalEncode encodes a decimal value into its fixed-width byte
+// representation for the given data type.
+func DecimalEncode(dt DataType, d decimal.Decimal) ([]byte, error) {
+	major, _ := DecomposeDataType(dt)
+	size := dt.Size()
+	c := d.Rescale(0).Coefficient()
+	if major == DataTypeMajorInt {
+		bits := int(size) * 8
+		if c.Sign() < 0 {
+			// Two's complement negative value, as DecimalDecode reads it.
+			c = new(big.Int).Add(c, pow2(uint(bits)))
+			if c.Sign() < 0 || c.BitLen() < bits {
+				return nil, fmt.Errorf("ast: value does not fit in %d bytes", size)
+			}
+		} else if c.BitLen() >= bits {
+			return nil, fmt.Errorf("ast: value does not fit in %d bytes", size)
+		}
+	}
+	b := c.Bytes()
+	if uint8(len(b)) > size {
+		return nil, fmt.Errorf("ast: value does not fit in %d bytes", size)
+	}
+	out := make([]byte, size)
+	copy(out[size-uint8(len(b)):], b)
+	return out, nil
+}
+
+// DecimalDecode decodes the fixed-width byte representation of a data type
+// back into a decimal value.
+func DecimalDecode(dt DataType, b []byte) (decimal.Decimal, error) {
+	major, _ := DecomposeDataType(dt)
+	v := decimal.NewFromBigInt(new(big.Int).SetBytes(b), 0)
+	if major == DataTypeMajorInt && len(b) > 0 && b[0]&0x80 != 0 {
+		// Two's complement negative value.
+		v = v.Sub(decimal.NewFromBigInt(pow2(uint(len(b)*8)), 0))
+	}
+	return v, nil
+}
@@ -0,0 +1,12 @@
+// Package decimal provides SQLVM-specific helpers on top of
+// github.com/dexon-foundation/decimal, such as canonical boolean values.
+package decimal
+
+import "github.com/dexon-foundation/decimal"
+
+// True and False are the canonical decimal representations of the boolean
+// values SQLVM stores in a single byte within a slot.
+var (
+	True  = decimal.New(1, 0)
+	False = decimal.New(0, 0)
+)
@@ -0,0 +1,70 @@
+package common
+
+import (
+	dexCommon "github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/vm/sqlvm/errors"
+)
+
+// Recognized storage layout versions. Version 1 is the original
+// byte-shift-within-a-slot scheme with Keccak-hashed pointers for dynamic
+// bytes; version 2 is a more compact encoding (inline varint lengths for
+// dynamic bytes, booleans packed as a bitmap across a row).
+const (
+	LayoutVersionUnset uint8 = 0
+	LayoutVersionV1    uint8 = 1
+	LayoutVersionV2    uint8 = 2
+
+	// CurrentLayoutVersion is the newest layout version defined here.
+	// Nothing in this package stamps it automatically: a contract stays
+	// unstamped, and reads as LayoutVersionV1, until StampLayoutVersion or
+	// MigrateLayout records a version for it.
+	CurrentLayoutVersion = LayoutVersionV2
+)
+
+// layoutVersionSlot is the well-known slot every SQLVM contract reserves
+// for its storage layout version byte. Row and sequence slots are always
+// derived from a Keccak256 hash of a (table, id) or (table, seq) pair, so
+// this fixed, un-hashed slot can never collide with column data.
+var layoutVersionSlot = dexCommon.HexToHash("0xff")
+
+// LayoutVersion returns the storage layout version addr was stamped with.
+// It reads the layout version slot on every call and keeps no cached
+// copy. A contract that has never stamped a version (new or pre-dating this
+// feature) implicitly speaks LayoutVersionV1.
+func (s *Storage) LayoutVersion(addr dexCommon.Address) (uint8, error) {
+	h := s.GetState(addr, layoutVersionSlot)
+	v := h.Bytes()[31]
+	if v == LayoutVersionUnset {
+		return LayoutVersionV1, nil
+	}
+	return v, nil
+}
+
+// StampLayoutVersion records v as addr's storage layout version, which
+// every later LayoutVersion call for addr, and so every later opLoad or
+// opLoadPage call, uses to interpret its rows. No runtime write path
+// calls it: a contract is stamped only by an explicit call to this
+// function or to MigrateLayout.
+func (s *Storage) StampLayoutVersion(addr dexCommon.Address, v uint8) {
+	var h dexCommon.Hash
+	h[31] = v
+	s.SetState(addr, layoutVersionSlot, h)
+}
+
+// MigrateLayout lets a contract opt into a new storage layout version: it
+// checks addr is currently stamped with `from` and, if so, restamps it as
+// `to`. It does not rewrite any existing row; rows already written under
+// `from` must already be readable under `to` (or have been rewritten by
+// the caller through the normal write path first), since every decode
+// call after this point will use `to`'s codec.
+func (s *Storage) MigrateLayout(addr dexCommon.Address, from, to uint8) error {
+	cur, err := s.LayoutVersion(addr)
+	if err != nil {
+		return err
+	}
+	if cur != from {
+		return errors.ErrorCodeUnsupportedLayoutVersion
+	}
+	s.StampLayoutVersion(addr, to)
+	return nil
+}
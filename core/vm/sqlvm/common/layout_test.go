@@ -0,0 +1,50 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	dexCommon "github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/state"
+	"github.com/dexon-foundation/dexon/core/vm/sqlvm/errors"
+	"github.com/dexon-foundation/dexon/ethdb"
+)
+
+func newTestStorage(t *testing.T) (*Storage, dexCommon.Address) {
+	db := ethdb.NewMemDatabase()
+	stateDB, err := state.New(dexCommon.Hash{}, state.NewDatabase(db))
+	require.NoError(t, err)
+	storage := NewStorage(stateDB)
+	addr := dexCommon.HexToAddress("0x9988")
+	storage.CreateAccount(addr)
+	return storage, addr
+}
+
+func TestMigrateLayout(t *testing.T) {
+	storage, addr := newTestStorage(t)
+
+	// A contract that has never stamped a version implicitly speaks V1.
+	version, err := storage.LayoutVersion(addr)
+	require.NoError(t, err)
+	require.Equal(t, LayoutVersionV1, version)
+
+	err = storage.MigrateLayout(addr, LayoutVersionV1, LayoutVersionV2)
+	require.NoError(t, err)
+
+	version, err = storage.LayoutVersion(addr)
+	require.NoError(t, err)
+	require.Equal(t, LayoutVersionV2, version)
+}
+
+func TestMigrateLayoutRejectsWrongFromVersion(t *testing.T) {
+	storage, addr := newTestStorage(t)
+	storage.StampLayoutVersion(addr, LayoutVersionV2)
+
+	err := storage.MigrateLayout(addr, LayoutVersionV1, LayoutVersionV2)
+	require.Equal(t, errors.ErrorCodeUnsupportedLayoutVersion, err)
+
+	version, err := storage.LayoutVersion(addr)
+	require.NoError(t, err)
+	require.Equal(t, LayoutVersionV2, version, "a rejected migration must not change the stamped version")
+}
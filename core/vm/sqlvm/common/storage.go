@@ -0,0 +1,83 @@
+// Package common holds the types shared across the SQLVM compiler and
+// runtime: the execution Context, and the Storage abstraction used to read
+// and write contract state.
+package common
+
+import (
+	"math/big"
+
+	dexCommon "github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/state"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/core/vm/sqlvm/schema"
+	"github.com/dexon-foundation/dexon/crypto"
+)
+
+// Option carries per-call execution flags for the runtime.
+type Option struct {
+	SafeMath bool
+}
+
+// Context bundles everything an instruction needs in order to execute: the
+// contract being run, its backing Storage, the active Schema, and Option
+// flags.
+type Context struct {
+	Contract *vm.Contract
+	Storage  *Storage
+	Opt      Option
+}
+
+// Storage wraps a state.StateDB with SQLVM's row/column addressing scheme.
+type Storage struct {
+	*state.StateDB
+	Schema schema.Schema
+}
+
+// NewStorage wraps an existing state.StateDB for SQLVM use.
+func NewStorage(db *state.StateDB) *Storage {
+	return &Storage{StateDB: db}
+}
+
+// ShiftHashUint64 returns the hash obtained by treating h as a big-endian
+// 256-bit integer and adding n to it. It is used to walk sequential slots
+// starting at a row or table head.
+func (s *Storage) ShiftHashUint64(h dexCommon.Hash, n uint64) dexCommon.Hash {
+	i := new(big.Int).SetBytes(h.Bytes())
+	i.Add(i, new(big.Int).SetUint64(n))
+	return dexCommon.BytesToHash(i.Bytes())
+}
+
+// GetRowPathHash returns the head slot hash for the row identified by id
+// within the given table.
+func (s *Storage) GetRowPathHash(t schema.TableRef, id uint64) dexCommon.Hash {
+	buf := make([]byte, 9)
+	buf[0] = byte(t)
+	idBytes := new(big.Int).SetUint64(id).Bytes()
+	copy(buf[9-len(idBytes):], idBytes)
+	return crypto.Keccak256Hash(buf)
+}
+
+// GetSequencePathHash returns the slot hash holding the current value of the
+// seqIdx-th auto-increment sequence in the given table.
+func (s *Storage) GetSequencePathHash(t schema.TableRef, seqIdx uint8) dexCommon.Hash {
+	buf := []byte{byte(t), 0xff, seqIdx}
+	return crypto.Keccak256Hash(buf)
+}
+
+// ReadBoundedSlots reads exactly maxSlots consecutive slots starting at
+// head. It bounds the work a single dynamic-bytes read or page of rows can
+// do to a caller-supplied ceiling instead of trusting an on-chain length
+// field to be well-formed; readLongDynamicBytes uses it to fetch a long
+// dynamic-bytes value's slots in one call instead of hand-rolling the
+// GetState/ShiftHashUint64 loop itself.
+func (s *Storage) ReadBoundedSlots(addr dexCommon.Address, head dexCommon.Hash,
+	maxSlots uint64) []dexCommon.Hash {
+
+	out := make([]dexCommon.Hash, maxSlots)
+	ptr := head
+	for i := uint64(0); i < maxSlots; i++ {
+		out[i] = s.GetState(addr, ptr)
+		ptr = s.ShiftHashUint64(ptr, 1)
+	}
+	return out
+}
@@ -0,0 +1,36 @@
+// Package errors defines the error codes returned by the SQLVM runtime.
+package errors
+
+import "fmt"
+
+// ErrorCode identifies a specific SQLVM runtime failure.
+type ErrorCode int
+
+// Error implements the error interface.
+func (e ErrorCode) Error() string {
+	if msg, ok := errorMessages[e]; ok {
+		return fmt.Sprintf("sqlvm: %s", msg)
+	}
+	return fmt.Sprintf("sqlvm: unknown error code %d", int(e))
+}
+
+// Recognized error codes.
+const (
+	ErrorCodeIndexOutOfRange ErrorCode = iota + 1
+	ErrorCodeOverflow
+	ErrorCodeCursorMismatch
+	ErrorCodeInvalidCursor
+	ErrorCodeUnsupportedLayoutVersion
+	ErrorCodeMalformedDynamicBytes
+	ErrorCodeUnsupportedOpcode
+)
+
+var errorMessages = map[ErrorCode]string{
+	ErrorCodeIndexOutOfRange:          "index out of range",
+	ErrorCodeOverflow:                 "value overflows destination type",
+	ErrorCodeCursorMismatch:           "resume cursor does not match ids/fields of this call",
+	ErrorCodeInvalidCursor:            "cursor is malformed or has an unsupported version",
+	ErrorCodeUnsupportedLayoutVersion: "storage layout version is not supported",
+	ErrorCodeMalformedDynamicBytes:    "dynamic bytes length header is malformed",
+	ErrorCodeUnsupportedOpcode:        "opcode has no registered handler",
+}
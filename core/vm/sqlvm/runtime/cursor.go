@@ -0,0 +1,97 @@
+package runtime
+
+import (
+	"github.com/dexon-foundation/decimal"
+
+	"github.com/dexon-foundation/dexon/core/vm/sqlvm/ast"
+	"github.com/dexon-foundation/dexon/core/vm/sqlvm/errors"
+	"github.com/dexon-foundation/dexon/core/vm/sqlvm/schema"
+)
+
+// cursorVersion1 is the only cursor wire format defined so far: version
+// byte, table ref, uint64 row index, 32-byte ids/fields digest.
+const cursorVersion1 = 1
+
+const (
+	cursorOffsetVersion  = 0
+	cursorOffsetTableRef = 1
+	cursorOffsetIndex    = 2
+	cursorOffsetDigest   = 10
+	cursorLength         = cursorOffsetDigest + 32
+)
+
+// cursor is the decoded form of the opaque resume token opLoadPage hands
+// back to callers: the row index to resume from, and enough of the
+// original call's shape (table and a digest of ids/fields) to detect a
+// caller resuming with a different query.
+//
+// index is an index into ids, not a combined (idIndex, fieldIndex) pair:
+// the fixed wire layout this cursor uses has room for exactly one uint64
+// index, and every page opLoadPage decodes is a whole row (every
+// requested field of every id in the page), never a partial row. There is
+// deliberately no field-level resume position.
+type cursor struct {
+	version  byte
+	tableRef schema.TableRef
+	index    uint64
+	digest   [32]byte
+}
+
+// encode packs the cursor into its fixed-layout wire format.
+func (c cursor) encode() []byte {
+	b := make([]byte, cursorLength)
+	b[cursorOffsetVersion] = c.version
+	b[cursorOffsetTableRef] = byte(c.tableRef)
+	for i := 0; i < 8; i++ {
+		b[cursorOffsetIndex+i] = byte(c.index >> uint(56-8*i))
+	}
+	copy(b[cursorOffsetDigest:], c.digest[:])
+	return b
+}
+
+// decodeCursor unpacks the fixed-layout wire format produced by encode.
+func decodeCursor(b []byte) (cursor, error) {
+	var c cursor
+	if len(b) != cursorLength {
+		return c, errors.ErrorCodeInvalidCursor
+	}
+	c.version = b[cursorOffsetVersion]
+	if c.version != cursorVersion1 {
+		return c, errors.ErrorCodeInvalidCursor
+	}
+	c.tableRef = schema.TableRef(b[cursorOffsetTableRef])
+	for i := 0; i < 8; i++ {
+		c.index = c.index<<8 | uint64(b[cursorOffsetIndex+i])
+	}
+	copy(c.digest[:], b[cursorOffsetDigest:])
+	return c, nil
+}
+
+// newCursorOperand wraps a cursor as the dynamic-bytes Operand opLoadPage
+// writes to its cursor output register.
+func newCursorOperand(c cursor) *Operand {
+	return &Operand{
+		Meta: []ast.DataType{ast.ComposeDataType(ast.DataTypeMajorDynamicBytes, 0)},
+		Data: []Tuple{{{Bytes: c.encode()}}},
+	}
+}
+
+// parseCursorOperand is the inverse of newCursorOperand: it reads back the
+// cursor a prior opLoadPage call returned so the next call can resume from
+// it.
+func parseCursorOperand(op *Operand) (cursor, error) {
+	if op == nil || len(op.Data) == 0 || len(op.Data[0]) == 0 {
+		return cursor{}, errors.ErrorCodeInvalidCursor
+	}
+	return decodeCursor(op.Data[0][0].Bytes)
+}
+
+// newPageSizeOperand builds the immediate operand opLoadPage's pageSize
+// input expects.
+func newPageSizeOperand(n uint64) *Operand {
+	return &Operand{
+		IsImmediate: true,
+		Meta:        []ast.DataType{ast.ComposeDataType(ast.DataTypeMajorUint, 7)},
+		Data:        []Tuple{{{Value: decimal.New(int64(n), 0)}}},
+	}
+}
@@ -0,0 +1,319 @@
+// Package runtime implements the SQLVM instruction set: decoding column
+// values out of contract storage and executing opcodes against registers.
+package runtime
+
+import (
+	"encoding/binary"
+
+	dexCommon "github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/vm/sqlvm/ast"
+	"github.com/dexon-foundation/dexon/core/vm/sqlvm/common"
+	dec "github.com/dexon-foundation/dexon/core/vm/sqlvm/common/decimal"
+	"github.com/dexon-foundation/dexon/core/vm/sqlvm/errors"
+	"github.com/dexon-foundation/dexon/core/vm/sqlvm/schema"
+	"github.com/dexon-foundation/dexon/crypto"
+)
+
+// Opcode identifies a single SQLVM instruction.
+type Opcode uint8
+
+// Recognized opcodes.
+const (
+	OpLoad Opcode = iota
+	OpLoadPage
+)
+
+// Instruction is a single decoded bytecode instruction: an opcode plus its
+// input and output register references.
+type Instruction struct {
+	Op     Opcode
+	Input  []*Operand
+	Output uint
+}
+
+// OpFunction is the signature every opcode handler implements: it reads its
+// operands from input/registers and writes its result into registers at
+// index output.
+type OpFunction func(ctx *common.Context, input []*Operand, registers []*Operand, output uint) error
+
+// opTable dispatches every Opcode to the OpFunction that implements it.
+var opTable = map[Opcode]OpFunction{
+	OpLoad:     opLoad,
+	OpLoadPage: opLoadPage,
+}
+
+// Execute runs a single decoded Instruction against registers, dispatching
+// on its Op through opTable.
+func Execute(ctx *common.Context, instr Instruction, registers []*Operand) error {
+	fn, ok := opTable[instr.Op]
+	if !ok {
+		return errors.ErrorCodeUnsupportedOpcode
+	}
+	return fn(ctx, instr.Input, registers, instr.Output)
+}
+
+// decode reads a single column value out of the slot at position slot,
+// whose raw bytes (already sliced to the column's byte range within the
+// slot) are given by b, under the given storage layout version. bit is
+// the column's BitOffset (-1 unless it is a LayoutVersionV2 bitmap-packed
+// bool). Scalars and fixed-size byte types decode identically under every
+// version; DataTypeMajorBool and DataTypeMajorDynamicBytes follow the
+// version's codec.
+func decode(ctx *common.Context, dt ast.DataType, slot dexCommon.Hash, b []byte, version uint8, bit int8) (*Raw, error) {
+	major, _ := ast.DecomposeDataType(dt)
+	switch major {
+	case ast.DataTypeMajorDynamicBytes:
+		if version >= common.LayoutVersionV2 {
+			return decodeDynamicBytesV2(ctx, slot, b)
+		}
+		return decodeDynamicBytesV1(ctx, slot, b)
+	case ast.DataTypeMajorUint, ast.DataTypeMajorInt:
+		v, err := ast.DecimalDecode(dt, b)
+		if err != nil {
+			return nil, err
+		}
+		return &Raw{Value: v}, nil
+	case ast.DataTypeMajorBool:
+		return decodeBool(b, bit), nil
+	default:
+		// DataTypeMajorFixedBytes, DataTypeMajorAddress.
+		return &Raw{Bytes: append([]byte{}, b...)}, nil
+	}
+}
+
+// decodeBool reads a boolean out of b. Under LayoutVersionV1 (bit < 0) the
+// column owns the whole byte; under the LayoutVersionV2 bitmap scheme it
+// owns a single bit of a byte shared with other bool columns.
+func decodeBool(b []byte, bit int8) *Raw {
+	var set bool
+	if bit < 0 {
+		set = len(b) > 0 && b[len(b)-1] != 0
+	} else {
+		set = len(b) > 0 && b[0]&(1<<uint(bit)) != 0
+	}
+	if set {
+		return &Raw{Value: dec.True}
+	}
+	return &Raw{Value: dec.False}
+}
+
+// decodeDynamicBytesV1 implements the v1 short/long string layout: the
+// last byte of the head slot is length*2 for values that fit inline (<32
+// bytes), or length*2+1 for values stored starting at keccak256(slot).
+func decodeDynamicBytesV1(ctx *common.Context, slot dexCommon.Hash, head []byte) (*Raw, error) {
+	last := head[len(head)-1]
+	length := uint64(last) / 2
+	if last&1 == 0 {
+		return &Raw{Bytes: append([]byte{}, head[:length]...)}, nil
+	}
+	return readLongDynamicBytes(ctx, slot, length)
+}
+
+// maxDynamicBytesLength caps the length a dynamic-bytes column's on-chain
+// header is allowed to claim. It is not a real contract data limit -- it
+// exists only so a malformed or adversarial length field (e.g. a 10-byte
+// varint encoding math.MaxUint64) is rejected as malformed before it is
+// ever converted to int or passed to make(), rather than wrapping
+// negative or driving a multi-gigabyte allocation. 1<<32 is already far
+// beyond any value a real contract would plausibly store.
+const maxDynamicBytesLength = 1 << 32
+
+// decodeDynamicBytesV2 implements the v2 layout: the head slot starts
+// with an unsigned varint encoding the value's length, immediately
+// followed by the value itself if it fits in the remaining bytes of the
+// slot. If it does not fit, nothing follows the varint and the value is
+// stored starting at keccak256(slot). Unlike v1 this
+// needs no extra flag bit: encode and decode apply the same fits check.
+func decodeDynamicBytesV2(ctx *common.Context, slot dexCommon.Hash, head []byte) (*Raw, error) {
+	length, n := binary.Uvarint(head)
+	if n <= 0 || length > maxDynamicBytesLength {
+		return nil, errors.ErrorCodeMalformedDynamicBytes
+	}
+	if uint64(n)+length <= uint64(len(head)) {
+		return &Raw{Bytes: append([]byte{}, head[n:uint64(n)+length]...)}, nil
+	}
+	return readLongDynamicBytes(ctx, slot, length)
+}
+
+// readLongDynamicBytes reads a value too large to fit in its head slot,
+// stored starting at keccak256(slot) and spanning ceil(length/32) slots.
+// Both storage layout versions chase this same pointer scheme. Callers
+// must already have validated length against maxDynamicBytesLength.
+func readLongDynamicBytes(ctx *common.Context, slot dexCommon.Hash, length uint64) (*Raw, error) {
+	if length > maxDynamicBytesLength {
+		return nil, errors.ErrorCodeMalformedDynamicBytes
+	}
+	addr := ctx.Contract.Address()
+	ptr := crypto.Keccak256Hash(slot.Bytes())
+	chunks := ctx.Storage.ReadBoundedSlots(addr, ptr, (length+31)/32)
+
+	out := make([]byte, 0, length)
+	remaining := length
+	for _, chunk := range chunks {
+		n := remaining
+		if n > 32 {
+			n = 32
+		}
+		out = append(out, chunk.Bytes()[:n]...)
+		remaining -= n
+	}
+	return &Raw{Bytes: out}, nil
+}
+
+// loadRow decodes a single row (the columns named by fields, in order) of
+// cols, starting at head, the row's head slot position, using the given
+// storage layout version.
+func loadRow(ctx *common.Context, addr dexCommon.Address, cols []schema.Column, head dexCommon.Hash, fields []uint8, version uint8) (Tuple, error) {
+	row := make(Tuple, len(fields))
+	for j, f := range fields {
+		col := cols[f]
+		slot := ctx.Storage.ShiftHashUint64(head, col.SlotOffset)
+		slotVal := ctx.Storage.GetState(addr, slot)
+		size := uint64(col.Type.Size())
+		start := uint64(col.ByteOffset)
+		b := slotVal.Bytes()[start : start+size]
+		raw, err := decode(ctx, col.Type, slot, b, version, col.BitOffset)
+		if err != nil {
+			return nil, err
+		}
+		row[j] = raw
+	}
+	return row, nil
+}
+
+// opLoad reads every field of every row named by ids out of the table
+// named by input[0] and decodes the entire result set into
+// registers[output] in one call.
+func opLoad(ctx *common.Context, input []*Operand, registers []*Operand, output uint) error {
+	tRef, ids, fields, err := decodeLoadArgs(ctx, input)
+	if err != nil {
+		return err
+	}
+	meta, data, _, err := loadRows(ctx, tRef, ids, fields, 0, uint64(len(ids)))
+	if err != nil {
+		return err
+	}
+	registers[output] = &Operand{Meta: meta, Data: data}
+	return nil
+}
+
+// opLoadPage is the paged counterpart of opLoad: it accepts the same
+// table/ids/fields operands (input[0:3]) as opLoad, plus a page-size hint
+// (input[3]) and an opaque resume cursor (input[4], nil on the first
+// call). It decodes at most pageSize rows starting at the position the
+// cursor encodes, writes the decoded rows to registers[output], and
+// writes the cursor for the next call to registers[output+1], or nil if
+// the ids list has been fully consumed.
+func opLoadPage(ctx *common.Context, input []*Operand, registers []*Operand, output uint) error {
+	tRef, ids, fields, err := decodeLoadArgs(ctx, input)
+	if err != nil {
+		return err
+	}
+	version, err := ctx.Storage.LayoutVersion(ctx.Contract.Address())
+	if err != nil {
+		return err
+	}
+	digest := cursorDigest(ids, fields, version)
+
+	start := uint64(0)
+	if len(input) > 4 && input[4] != nil && len(input[4].Data) > 0 {
+		cur, err := parseCursorOperand(input[4])
+		if err != nil {
+			return err
+		}
+		if cur.tableRef != tRef || cur.digest != digest {
+			return errors.ErrorCodeCursorMismatch
+		}
+		start = cur.index
+	}
+
+	pageSize := uint64(len(ids))
+	if len(input) > 3 && input[3] != nil && len(input[3].Data) > 0 {
+		pageSize = input[3].Data[0][0].Value.Rescale(0).Coefficient().Uint64()
+	}
+
+	meta, data, next, err := loadRows(ctx, tRef, ids, fields, start, pageSize)
+	if err != nil {
+		return err
+	}
+	registers[output] = &Operand{Meta: meta, Data: data}
+
+	if next >= uint64(len(ids)) {
+		registers[output+1] = nil
+	} else {
+		registers[output+1] = newCursorOperand(cursor{
+			version: cursorVersion1, tableRef: tRef, index: next, digest: digest,
+		})
+	}
+	return nil
+}
+
+// decodeLoadArgs validates and unpacks the table/ids/fields operands
+// shared by opLoad and opLoadPage.
+func decodeLoadArgs(ctx *common.Context, input []*Operand) (schema.TableRef, []uint64, []uint8, error) {
+	tRef, err := tableRef(ctx, input[0])
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	return tRef, input[1].uint64Values(), input[2].uint8Values(), nil
+}
+
+// loadRows decodes up to pageSize rows of table tRef, named by ids[start:],
+// returning the column metadata for fields, the decoded rows, and the
+// index to resume from on the next call (== len(ids) at end of stream).
+// It reads the contract's negotiated storage layout version once up
+// front, the same way a CQL driver reads ProtoVersion once per
+// connection, and decodes every row of the call under that version.
+func loadRows(ctx *common.Context, tRef schema.TableRef, ids []uint64, fields []uint8,
+	start, pageSize uint64) ([]ast.DataType, []Tuple, uint64, error) {
+
+	table := ctx.Storage.Schema[tRef]
+	for _, f := range fields {
+		if int(f) >= len(table.Columns) {
+			return nil, nil, 0, errors.ErrorCodeIndexOutOfRange
+		}
+	}
+
+	meta := make([]ast.DataType, len(fields))
+	for i, f := range fields {
+		meta[i] = table.Columns[f].Type
+	}
+
+	addr := ctx.Contract.Address()
+	version, err := ctx.Storage.LayoutVersion(addr)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	data := make([]Tuple, 0, pageSize)
+	i := start
+	for ; i < uint64(len(ids)) && uint64(len(data)) < pageSize; i++ {
+		head := ctx.Storage.GetRowPathHash(tRef, ids[i])
+		row, err := loadRow(ctx, addr, table.Columns, head, fields, version)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		data = append(data, row)
+	}
+	return meta, data, i, nil
+}
+
+// cursorDigest hashes the shape of a paged call: its ids, its fields, and
+// the storage layout version in effect when the cursor was issued. Folding
+// the layout version in means a cursor resumed after a MigrateLayout call
+// between pages is rejected the same way a resume with different ids or
+// fields is, rather than silently decoding the remaining rows under a
+// codec that no longer matches how the first page was read.
+func cursorDigest(ids []uint64, fields []uint8, version uint8) [32]byte {
+	buf := make([]byte, 0, len(ids)*8+len(fields)+1)
+	for _, id := range ids {
+		buf = append(buf,
+			byte(id>>56), byte(id>>48), byte(id>>40), byte(id>>32),
+			byte(id>>24), byte(id>>16), byte(id>>8), byte(id))
+	}
+	buf = append(buf, fields...)
+	buf = append(buf, version)
+	var out [32]byte
+	copy(out[:], crypto.Keccak256(buf))
+	return out
+}
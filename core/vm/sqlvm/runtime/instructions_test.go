@@ -38,7 +38,7 @@ type raw struct {
 	minor     ast.DataTypeMinor
 }
 
-func createSchema(storage *common.Storage, raws []*raw) {
+func createSchema(storage *common.Storage, raws []*raw, version uint8) {
 	storage.Schema = schema.Schema{
 		schema.Table{
 			Name: []byte("Table_A"),
@@ -58,23 +58,53 @@ func createSchema(storage *common.Storage, raws []*raw) {
 			0, nil, 0, nil,
 		)
 	}
-	storage.Schema.SetupColumnOffset()
+	storage.Schema.SetupColumnOffset(version)
 }
 
-// setSlotDataInStateDB store data in StateDB, and
-// return corresponding slot hash and raw slice.
+// slotHashV1/slotHashV2 are the six raw 32-byte slots setSlotDataInStateDB
+// plants, one per distinct slotShift used by raws. Slots 2 (fixed bytes) and
+// 5 (uint256) decode identically under every version and are shared
+// verbatim; slot 0's bool byte and slots 1/3/4's dynamic-bytes headers
+// differ: V1 trails a length*2(+1) flag byte, V2 leads with a varint length
+// and packs the bool into a single bit instead of owning a whole byte.
+var slotHashV1 = []string{
+	"0123112233445566778800000000000000000000000000000000000000000000",
+	"48656c6c6f2c20776f726c64210000000000000000000000000000000000001a",
+	"3132333435363738393000000000000000000000000000000000000000000000",
+	"53514c564d2069732075736566756c2100000000000000000000000000000020",
+	"0000000000000000000000000000000000000000000000000000000000000041",
+	"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+}
+
+var slotHashV2 = []string{
+	"0123112233445566778801000000000000000000000000000000000000000000",
+	"0d48656c6c6f2c20776f726c6421000000000000000000000000000000000000",
+	"3132333435363738393000000000000000000000000000000000000000000000",
+	"1053514c564d2069732075736566756c21000000000000000000000000000000",
+	"2000000000000000000000000000000000000000000000000000000000000000",
+	"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+}
+
+// boolBitOffset is the bit decodeTestCases use to exercise the
+// LayoutVersionV2 bitmap-packed bool decode path. It doesn't need to match
+// any real schema's computed BitOffset -- setSlotDataInStateDB/V2 just
+// needs to plant a byte whose other bits are noise, so decode() is proven
+// to read only the bit it's told to.
+const boolBitOffset = 3
+
+// setSlotDataInStateDB stores data in StateDB encoded for the given storage
+// layout version, and returns the corresponding slot hash and raw slice.
+// Every raw's semantic value is identical across versions; only the wire
+// encoding of its DynamicBytes/Bool entries (slot 0's bool byte, slots 1/3/4's
+// dynamic-bytes headers) differs.
 func setSlotDataInStateDB(head dexCommon.Hash, addr dexCommon.Address,
-	storage *common.Storage) ([]dexCommon.Hash, []*raw) {
+	storage *common.Storage, version uint8) ([]dexCommon.Hash, []*raw) {
 
 	hash := dexCommon.Hash{}
 	var b []byte
-	slotHash := []string{
-		"0123112233445566778800000000000000000000000000000000000000000000",
-		"48656c6c6f2c20776f726c64210000000000000000000000000000000000001a",
-		"3132333435363738393000000000000000000000000000000000000000000000",
-		"53514c564d2069732075736566756c2100000000000000000000000000000020",
-		"0000000000000000000000000000000000000000000000000000000000000041",
-		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+	slotHash := slotHashV1
+	if version >= common.LayoutVersionV2 {
+		slotHash = slotHashV2
 	}
 	uInt256Dt := ast.ComposeDataType(ast.DataTypeMajorUint, ast.DataTypeMinor(31))
 
@@ -196,6 +226,7 @@ type decodeTestCase struct {
 	shift          uint64
 	inputBytes     []byte
 	dBytes         []byte
+	bit            int8
 }
 
 type opLoadTestCase struct {
@@ -217,14 +248,15 @@ func (s *opLoadSuite) SetupTest() {
 	s.ctx.Storage.CreateAccount(s.address)
 	s.ctx.Contract = vm.NewContract(vm.AccountRef(s.address),
 		vm.AccountRef(s.address), new(big.Int), 0)
-	s.slotHash, s.raws = setSlotDataInStateDB(s.headHash, s.address, s.ctx.Storage)
-	createSchema(s.ctx.Storage, s.raws)
+	s.slotHash, s.raws = setSlotDataInStateDB(s.headHash, s.address, s.ctx.Storage, common.LayoutVersionV1)
+	s.ctx.Storage.StampLayoutVersion(s.address, common.LayoutVersionV1)
+	createSchema(s.ctx.Storage, s.raws, common.LayoutVersionV1)
 	s.setColData(targetTableRef, 654321)
 }
 
 func (s *opLoadSuite) setColData(tableRef schema.TableRef, id uint64) {
 	h := s.ctx.Storage.GetRowPathHash(tableRef, id)
-	setSlotDataInStateDB(h, s.address, s.ctx.Storage)
+	setSlotDataInStateDB(h, s.address, s.ctx.Storage, common.LayoutVersionV1)
 }
 
 func (s *opLoadSuite) getOpLoadTestCases(raws []*raw) []opLoadTestCase {
@@ -284,10 +316,11 @@ func (s *opLoadSuite) getOKCaseFields(raws []*raw) []uint8 {
 }
 
 func (s *opLoadSuite) getDecodeTestCases(headHash dexCommon.Hash,
-	address dexCommon.Address, storage *common.Storage) []decodeTestCase {
+	address dexCommon.Address, storage *common.Storage, version uint8) []decodeTestCase {
 
-	slotHash, raws := setSlotDataInStateDB(headHash, address, storage)
-	createSchema(storage, raws)
+	slotHash, raws := setSlotDataInStateDB(headHash, address, storage, version)
+	storage.StampLayoutVersion(address, version)
+	createSchema(storage, raws, version)
 	testCases := make([]decodeTestCase, len(raws))
 
 	for i := range testCases {
@@ -296,6 +329,10 @@ func (s *opLoadSuite) getDecodeTestCases(headHash dexCommon.Hash,
 		testCases[i].shift = uint64(r.slotShift)
 		testCases[i].expectSlotHash = slotHash[r.slotShift]
 		testCases[i].expectData = &r.Raw
+		testCases[i].bit = -1
+		if r.major == ast.DataTypeMajorBool && version >= common.LayoutVersionV2 {
+			testCases[i].bit = boolBitOffset
+		}
 		slot := slotHash[r.slotShift]
 		start := r.byteShift
 		end := r.byteShift + testCases[i].dt.Size()
@@ -382,20 +419,22 @@ func newStorage() *common.Storage {
 }
 
 func (s *opLoadSuite) TestDecode() {
-	testCases := s.getDecodeTestCases(s.headHash, s.address, s.ctx.Storage)
-	for _, tt := range testCases {
-		M, _ := ast.DecomposeDataType(tt.dt)
-		slot := s.ctx.Storage.ShiftHashUint64(s.headHash, tt.shift)
-		slotHash := s.ctx.Storage.GetState(s.address, slot)
-		s.Require().Equal(tt.expectSlotHash, slotHash)
-
-		data, err := decode(s.ctx, tt.dt, slot, tt.inputBytes)
-		s.Require().Nil(err)
-
-		if M == ast.DataTypeMajorDynamicBytes {
-			s.Require().Equal(tt.expectData.Bytes, data.Bytes)
-		} else {
-			s.Require().True(tt.expectData.Value.Equal(data.Value))
+	for _, version := range []uint8{common.LayoutVersionV1, common.LayoutVersionV2} {
+		testCases := s.getDecodeTestCases(s.headHash, s.address, s.ctx.Storage, version)
+		for _, tt := range testCases {
+			M, _ := ast.DecomposeDataType(tt.dt)
+			slot := s.ctx.Storage.ShiftHashUint64(s.headHash, tt.shift)
+			slotHash := s.ctx.Storage.GetState(s.address, slot)
+			s.Require().Equal(tt.expectSlotHash, slotHash)
+
+			data, err := decode(s.ctx, tt.dt, slot, tt.inputBytes, version, tt.bit)
+			s.Require().Nil(err)
+
+			if M == ast.DataTypeMajorDynamicBytes {
+				s.Require().Equal(tt.expectData.Bytes, data.Bytes)
+			} else {
+				s.Require().True(tt.expectData.Value.Equal(data.Value))
+			}
 		}
 	}
 }
@@ -417,6 +456,96 @@ func (s *opLoadSuite) TestOpLoad() {
 	}
 }
 
+// newPageRegisters lays out a register file wide enough for opLoadPage:
+// 0 result, 1 next cursor, 2 table, 3 ids, 4 fields, 5 page size, 6 cursor.
+func (s *opLoadSuite) newPageRegisters(tableIdx int8, ids []uint64, fields []uint8, pageSize uint64, in *Operand) []*Operand {
+	reg := make([]*Operand, 7)
+	reg[2] = newTableNameOperand(tableIdx)
+	reg[3] = newIDsOperand(ids)
+	reg[4] = newFieldsOperand(fields)
+	reg[5] = newPageSizeOperand(pageSize)
+	reg[6] = in
+	return reg
+}
+
+func (s *opLoadSuite) pageInput() []*Operand {
+	return newInput([]int{2, 3, 4, 5, 6})
+}
+
+func (s *opLoadSuite) TestOpLoadPageMultiPage() {
+	ids := []uint64{123456, 654321}
+	fields := s.getOKCaseFields(s.raws)
+	full := s.getOKCaseOutput(s.raws)
+
+	reg := s.newPageRegisters(1, ids, fields, 1, nil)
+	input := s.pageInput()
+	loadRegister(input, reg)
+
+	err := opLoadPage(s.ctx, input, reg, 0)
+	s.Require().Nil(err)
+	s.Require().Len(reg[0].Data, 1)
+	s.Require().Truef(reg[0].Equal(&Operand{Meta: full.Meta, Data: full.Data[:1]}),
+		"first page, got: %+v", reg[0])
+	s.Require().NotNil(reg[1], "expected a resume cursor after a partial page")
+
+	reg2 := s.newPageRegisters(1, ids, fields, 1, reg[1])
+	input2 := s.pageInput()
+	loadRegister(input2, reg2)
+
+	err = opLoadPage(s.ctx, input2, reg2, 0)
+	s.Require().Nil(err)
+	s.Require().Len(reg2[0].Data, 1)
+	s.Require().Truef(reg2[0].Equal(&Operand{Meta: full.Meta, Data: full.Data[1:]}),
+		"second page, got: %+v", reg2[0])
+	s.Require().Nil(reg2[1], "expected end-of-stream cursor after the last row")
+}
+
+func (s *opLoadSuite) TestOpLoadPageResumeMismatch() {
+	ids := []uint64{123456, 654321}
+	fields := s.getOKCaseFields(s.raws)
+
+	reg := s.newPageRegisters(1, ids, fields, 1, nil)
+	input := s.pageInput()
+	loadRegister(input, reg)
+	s.Require().Nil(opLoadPage(s.ctx, input, reg, 0))
+	cursorFromFirstCall := reg[1]
+
+	// Resuming against a different fields list must be rejected: the
+	// cursor's digest no longer matches, so the new call could silently
+	// resume into the wrong columns.
+	changedFields := append(append([]uint8{}, fields...), fields[0])
+	reg2 := s.newPageRegisters(1, ids, changedFields, 1, cursorFromFirstCall)
+	input2 := s.pageInput()
+	loadRegister(input2, reg2)
+
+	err := opLoadPage(s.ctx, input2, reg2, 0)
+	s.Require().Equal(errors.ErrorCodeCursorMismatch, err)
+}
+
+func (s *opLoadSuite) TestOpLoadPageResumeAfterLayoutChange() {
+	ids := []uint64{123456, 654321}
+	fields := s.getOKCaseFields(s.raws)
+
+	reg := s.newPageRegisters(1, ids, fields, 1, nil)
+	input := s.pageInput()
+	loadRegister(input, reg)
+	s.Require().Nil(opLoadPage(s.ctx, input, reg, 0))
+	cursorFromFirstCall := reg[1]
+
+	// A contract that migrates to a new storage layout version between
+	// pages must reject the old cursor: it was issued under a digest
+	// that no longer matches, so resuming under it would silently decode
+	// the remaining rows with the wrong codec.
+	s.ctx.Storage.StampLayoutVersion(s.address, common.LayoutVersionV2)
+
+	reg2 := s.newPageRegisters(1, ids, fields, 1, cursorFromFirstCall)
+	input2 := s.pageInput()
+	loadRegister(input2, reg2)
+
+	err := opLoadPage(s.ctx, input2, reg2, 0)
+	s.Require().Equal(errors.ErrorCodeCursorMismatch, err)
+}
+
 func makeOperand(im bool, meta []ast.DataType, pTuple []Tuple) (op *Operand) {
 	op = &Operand{IsImmediate: im, Meta: meta, Data: pTuple}
 	return
@@ -543,7 +672,7 @@ func (s *autoIncSuite) SetupTest() {
 	}
 	s.SetOverflow(1, 0, ast.ComposeDataType(ast.DataTypeMajorInt, 0))
 	s.SetOverflow(2, 0, ast.ComposeDataType(ast.DataTypeMajorUint, 0))
-	s.ctx.Storage.Schema.SetupColumnOffset()
+	s.ctx.Storage.Schema.SetupColumnOffset(common.LayoutVersionV1)
 }
 
 func (s *autoIncSuite) SetOverflow(tableRef schema.TableRef, seqIdx uint8, dt ast.DataType) {
@@ -782,7 +911,7 @@ func (s *setDefaultSuite) SetupTest() {
 			},
 		},
 	}
-	s.ctx.Storage.Schema.SetupColumnOffset()
+	s.ctx.Storage.Schema.SetupColumnOffset(common.LayoutVersionV1)
 }
 
 func (s *setDefaultSuite) TestFillDefault() {
@@ -957,9 +1086,97 @@ func (s *setDefaultSuite) TestFillDefault() {
 	}
 }
 
+// layoutV2Suite exercises the LayoutVersionV2 codec: inline varint lengths
+// for dynamic bytes and bitmap-packed booleans, in contrast to the
+// LayoutVersionV1 fixtures opLoadSuite covers.
+type layoutV2Suite struct {
+	suite.Suite
+	ctx     *common.Context
+	address dexCommon.Address
+	table   schema.TableRef
+}
+
+func (s *layoutV2Suite) SetupTest() {
+	s.ctx = &common.Context{}
+	s.ctx.Storage = newStorage()
+	s.address = dexCommon.HexToAddress("0x7766")
+	s.ctx.Storage.CreateAccount(s.address)
+	s.ctx.Contract = vm.NewContract(vm.AccountRef(s.address),
+		vm.AccountRef(s.address), new(big.Int), 0)
+
+	s.table = schema.TableRef(0)
+	s.ctx.Storage.Schema = schema.Schema{
+		schema.Table{
+			Name: []byte("v2_table"),
+			Columns: []schema.Column{
+				schema.NewColumn([]byte("c0"), ast.ComposeDataType(ast.DataTypeMajorUint, 0), 0, nil, 0, nil),
+				schema.NewColumn([]byte("c1"), ast.ComposeDataType(ast.DataTypeMajorBool, 0), 0, nil, 0, nil),
+				schema.NewColumn([]byte("c2"), ast.ComposeDataType(ast.DataTypeMajorBool, 0), 0, nil, 0, nil),
+				schema.NewColumn([]byte("c3"), ast.ComposeDataType(ast.DataTypeMajorDynamicBytes, 0), 0, nil, 0, nil),
+			},
+		},
+	}
+	s.ctx.Storage.Schema.SetupColumnOffset(common.LayoutVersionV2)
+	s.ctx.Storage.StampLayoutVersion(s.address, common.LayoutVersionV2)
+}
+
+func (s *layoutV2Suite) TestColumnOffsets() {
+	cols := s.ctx.Storage.Schema[s.table].Columns
+	s.Require().Equal(uint64(0), cols[0].SlotOffset)
+	s.Require().Equal(uint8(0), cols[0].ByteOffset)
+	s.Require().Equal(int8(-1), cols[0].BitOffset)
+
+	// c1/c2 are bitmap-packed into the same byte, not given a byte each.
+	s.Require().True(cols[1].IsBitmapPacked())
+	s.Require().True(cols[2].IsBitmapPacked())
+	s.Require().Equal(cols[1].SlotOffset, cols[2].SlotOffset)
+	s.Require().Equal(cols[1].ByteOffset, cols[2].ByteOffset)
+	s.Require().NotEqual(cols[1].BitOffset, cols[2].BitOffset)
+
+	s.Require().False(cols[3].IsBitmapPacked())
+}
+
+func (s *layoutV2Suite) TestDecodeRow() {
+	id := uint64(1)
+	head := s.ctx.Storage.GetRowPathHash(s.table, id)
+	cols := s.ctx.Storage.Schema[s.table].Columns
+
+	// c0: uint, value 7.
+	setByte(s.ctx.Storage, s.address, head, cols[0], 7)
+
+	// c1/c2: bitmap byte with only c1 set.
+	bitmap := byte(0)
+	bitmap |= 1 << uint(cols[1].BitOffset)
+	setByte(s.ctx.Storage, s.address, head, cols[1], bitmap)
+
+	// c3: short dynamic bytes, varint(2) header followed by "hi".
+	dbSlot := s.ctx.Storage.ShiftHashUint64(head, cols[3].SlotOffset)
+	var dbHead dexCommon.Hash
+	dbHead[0] = 2
+	copy(dbHead[1:], "hi")
+	s.ctx.Storage.SetState(s.address, dbSlot, dbHead)
+
+	row, err := loadRow(s.ctx, s.address, cols, head, []uint8{0, 1, 2, 3}, common.LayoutVersionV2)
+	s.Require().Nil(err)
+	s.Require().True(row[0].Value.Equal(decimal.New(7, 0)))
+	s.Require().True(row[1].Value.Equal(dec.True))
+	s.Require().True(row[2].Value.Equal(dec.False))
+	s.Require().Equal([]byte("hi"), row[3].Bytes)
+}
+
+// setByte writes a single byte at col's slot/byte offset within head,
+// leaving the rest of the slot zero.
+func setByte(storage *common.Storage, addr dexCommon.Address, head dexCommon.Hash, col schema.Column, b byte) {
+	slot := storage.ShiftHashUint64(head, col.SlotOffset)
+	var h dexCommon.Hash
+	h[col.ByteOffset] = b
+	storage.SetState(addr, slot, h)
+}
+
 func TestInstructions(t *testing.T) {
 	suite.Run(t, new(instructionSuite))
 	suite.Run(t, new(opLoadSuite))
 	suite.Run(t, new(autoIncSuite))
 	suite.Run(t, new(setDefaultSuite))
+	suite.Run(t, new(layoutV2Suite))
 }
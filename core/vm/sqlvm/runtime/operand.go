@@ -0,0 +1,187 @@
+package runtime
+
+import (
+	"github.com/dexon-foundation/decimal"
+
+	dexCommon "github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/vm/sqlvm/ast"
+	"github.com/dexon-foundation/dexon/core/vm/sqlvm/common"
+	"github.com/dexon-foundation/dexon/core/vm/sqlvm/errors"
+	"github.com/dexon-foundation/dexon/core/vm/sqlvm/schema"
+)
+
+// Raw is a single decoded column value. Scalar types use Value, while
+// address/fixed-bytes/dynamic-bytes types use Bytes.
+type Raw struct {
+	Value decimal.Decimal
+	Bytes []byte
+}
+
+// Equal reports whether two Raw values hold the same data.
+func (r *Raw) Equal(o *Raw) bool {
+	if r == nil || o == nil {
+		return r == o
+	}
+	if r.Bytes != nil || o.Bytes != nil {
+		return dexCommon.Bytes2Hex(r.Bytes) == dexCommon.Bytes2Hex(o.Bytes)
+	}
+	return r.Value.Equal(o.Value)
+}
+
+// Tuple is a single row: one Raw value per field, in field order.
+type Tuple []*Raw
+
+// Operand is the value held by a VM register: a typed table of rows, or an
+// immediate scalar/list value referenced directly from an instruction.
+type Operand struct {
+	IsImmediate   bool
+	RegisterIndex uint
+	Meta          []ast.DataType
+	Data          []Tuple
+}
+
+// Equal reports whether two Operands hold the same meta and data.
+func (o *Operand) Equal(p *Operand) bool {
+	if o == nil || p == nil {
+		return o == p
+	}
+	if len(o.Meta) != len(p.Meta) || len(o.Data) != len(p.Data) {
+		return false
+	}
+	for i := range o.Meta {
+		if o.Meta[i] != p.Meta[i] {
+			return false
+		}
+	}
+	for i := range o.Data {
+		if len(o.Data[i]) != len(p.Data[i]) {
+			return false
+		}
+		for j := range o.Data[i] {
+			if !o.Data[i][j].Equal(p.Data[i][j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// uint64Values decodes an Operand holding a single column of uint values,
+// such as the ids or fields operands passed to opLoad.
+func (o *Operand) uint64Values() []uint64 {
+	out := make([]uint64, len(o.Data))
+	for i, t := range o.Data {
+		out[i] = uint64(t[0].Value.IntPart())
+	}
+	return out
+}
+
+func (o *Operand) uint8Values() []uint8 {
+	out := make([]uint8, len(o.Data))
+	for i, t := range o.Data {
+		out[i] = uint8(t[0].Value.IntPart())
+	}
+	return out
+}
+
+// tableRef decodes the table index operand produced by newTableNameOperand,
+// validating it against the active schema.
+func tableRef(ctx *common.Context, op *Operand) (schema.TableRef, error) {
+	idx := op.Data[0][0].Value.IntPart()
+	if idx < 0 || int(idx) >= len(ctx.Storage.Schema) {
+		return 0, errors.ErrorCodeIndexOutOfRange
+	}
+	return schema.TableRef(idx), nil
+}
+
+// fillAutoInc fills in values for every column of tableRef that has an
+// auto-increment sequence and is missing from input's field list, bumping
+// each sequence counter in storage as it goes. It returns the filled-in
+// values as an Operand, the indices of the columns it filled (as an
+// Operand, so callers can splice them back into a field list), and an
+// error if a sequence has overflowed its column's data type.
+func (o *Operand) fillAutoInc(ctx *common.Context, t schema.TableRef) (*Operand, []*Operand, error) {
+	table := ctx.Storage.Schema[t]
+	field := &Operand{
+		Meta: []ast.DataType{ast.ComposeDataType(ast.DataTypeMajorUint, 0)},
+		Data: []Tuple{},
+	}
+	result := make([]*Operand, 0)
+
+	skip := make(map[uint8]bool)
+	for _, t := range o.Data {
+		skip[uint8(t[0].Value.IntPart())] = true
+	}
+
+	for i := range table.Columns {
+		col := &table.Columns[i]
+		if !col.HasSequence() || skip[uint8(i)] {
+			continue
+		}
+		seqPath := ctx.Storage.GetSequencePathHash(t, col.SeqOrGroup)
+		cur := ctx.Storage.GetState(ctx.Contract.Address(), seqPath)
+		val, err := ast.DecimalDecode(col.Type, cur.Bytes())
+		if err != nil {
+			return nil, nil, err
+		}
+		next := val.Add(decimal.New(1, 0))
+		_, max, err := col.Type.GetMinMax()
+		if err != nil {
+			return nil, nil, err
+		}
+		if next.GreaterThan(max) {
+			return nil, nil, errors.ErrorCodeOverflow
+		}
+		nb, err := ast.DecimalEncode(col.Type, next)
+		if err != nil {
+			return nil, nil, err
+		}
+		ctx.Storage.SetState(ctx.Contract.Address(), seqPath, dexCommon.BytesToHash(nb))
+
+		field.Data = append(field.Data, Tuple{{Value: decimal.New(int64(i), 0)}})
+		result = append(result, &Operand{
+			Meta: []ast.DataType{col.Type},
+			Data: []Tuple{{{Value: next}}},
+		})
+	}
+	return field, result, nil
+}
+
+// fillDefault fills in default values for every column of tableRef that has
+// one and is missing from input's field list. It mirrors fillAutoInc's
+// shape so both can be composed when building an INSERT row.
+func (o *Operand) fillDefault(ctx *common.Context, t schema.TableRef) (*Operand, []*Operand, error) {
+	table := ctx.Storage.Schema[t]
+	field := &Operand{
+		Meta: []ast.DataType{ast.ComposeDataType(ast.DataTypeMajorUint, 0)},
+		Data: []Tuple{},
+	}
+	result := make([]*Operand, 0)
+
+	skip := make(map[uint8]bool)
+	for _, t := range o.Data {
+		skip[uint8(t[0].Value.IntPart())] = true
+	}
+
+	for i := range table.Columns {
+		col := &table.Columns[i]
+		if !col.HasDefault() || skip[uint8(i)] {
+			continue
+		}
+		field.Data = append(field.Data, Tuple{{Value: decimal.New(int64(i), 0)}})
+		raw := &Raw{}
+		switch d := col.Default.(type) {
+		case decimal.Decimal:
+			raw.Value = d
+		case []byte:
+			raw.Bytes = d
+		default:
+			raw.Bytes = nil
+		}
+		result = append(result, &Operand{
+			Meta: []ast.DataType{col.Type},
+			Data: []Tuple{{raw}},
+		})
+	}
+	return field, result, nil
+}
@@ -0,0 +1,175 @@
+// Package schema describes the logical table/column layout of a SQLVM
+// contract, independent of how that layout is packed into storage slots.
+package schema
+
+import (
+	"github.com/dexon-foundation/dexon/core/vm/sqlvm/ast"
+)
+
+// TableRef identifies a table within a contract's Schema by its index.
+type TableRef uint8
+
+// ColumnAttr is a bitmask of attributes attached to a Column.
+type ColumnAttr uint8
+
+// Recognized column attributes.
+const (
+	ColumnAttrHasSequence ColumnAttr = 1 << iota
+	ColumnAttrHasDefault
+)
+
+// Column describes a single column of a Table: its name, its SQLVM data
+// type, any attributes, and the slot offset it has been assigned by
+// SetupColumnOffset.
+type Column struct {
+	Name       []byte
+	Type       ast.DataType
+	Attr       ColumnAttr
+	Rel        *TableRef
+	SeqOrGroup uint8
+	Default    interface{}
+	SlotOffset uint64
+	ByteOffset uint8
+
+	// BitOffset is the bit (0-7, within the byte at SlotOffset/ByteOffset)
+	// a DataTypeMajorBool column was packed into under the
+	// LayoutVersionV2 bitmap scheme. It is -1 for every column under
+	// LayoutVersionV1, and for every non-bool column under any version.
+	BitOffset int8
+}
+
+// IsBitmapPacked reports whether this column was assigned a bit within a
+// shared bitmap byte rather than a byte of its own.
+func (c Column) IsBitmapPacked() bool { return c.BitOffset >= 0 }
+
+// NewColumn constructs a Column. seqOrGroup carries the sequence index for
+// ColumnAttrHasSequence columns, and is otherwise unused padding kept for
+// layout symmetry with auto-increment bookkeeping.
+func NewColumn(name []byte, dt ast.DataType, attr ColumnAttr, rel *TableRef,
+	seqOrGroup uint8, defaultValue interface{}) Column {
+
+	return Column{
+		Name:       name,
+		Type:       dt,
+		Attr:       attr,
+		Rel:        rel,
+		SeqOrGroup: seqOrGroup,
+		Default:    defaultValue,
+		BitOffset:  -1,
+	}
+}
+
+// HasSequence reports whether the column is backed by an auto-increment
+// sequence.
+func (c Column) HasSequence() bool { return c.Attr&ColumnAttrHasSequence != 0 }
+
+// HasDefault reports whether the column has a default value to fill in when
+// omitted from an INSERT.
+func (c Column) HasDefault() bool { return c.Attr&ColumnAttrHasDefault != 0 }
+
+// Table is a single table within a contract's Schema.
+type Table struct {
+	Name    []byte
+	Columns []Column
+}
+
+// Schema is the ordered list of tables defined by a contract.
+type Schema []Table
+
+// SetupColumnOffset walks every table and assigns each column the slot and
+// byte offset it occupies. Column offsets are a function of the storage
+// layout version: under LayoutVersionV1 every column, booleans included,
+// packs into 32-byte slots in declaration order the same way Solidity
+// packs storage variables; under LayoutVersionV2 non-bool columns pack the
+// same way, but DataTypeMajorBool columns are pulled out of that stream
+// and packed 8-to-a-byte into a trailing bitmap instead, so offsets must
+// be recomputed for each version a contract may run under.
+func (s Schema) SetupColumnOffset(version uint8) {
+	for t := range s {
+		if version >= 2 {
+			s.setupColumnOffsetV2(t)
+		} else {
+			s.setupColumnOffsetV1(t)
+		}
+	}
+}
+
+func (s Schema) setupColumnOffsetV1(t int) {
+	var slot uint64
+	var byteOffset uint8
+	for c := range s[t].Columns {
+		size := s[t].Columns[c].Type.Size()
+		if size > 32 {
+			size = 32
+		}
+		if uint16(byteOffset)+uint16(size) > 32 {
+			slot++
+			byteOffset = 0
+		}
+		s[t].Columns[c].SlotOffset = slot
+		s[t].Columns[c].ByteOffset = byteOffset
+		s[t].Columns[c].BitOffset = -1
+		byteOffset += size
+		if byteOffset >= 32 {
+			slot++
+			byteOffset = 0
+		}
+	}
+}
+
+func (s Schema) setupColumnOffsetV2(t int) {
+	var slot uint64
+	var byteOffset uint8
+	var bools []int
+
+	place := func(c, size int) {
+		if uint64(byteOffset)+uint64(size) > 32 {
+			slot++
+			byteOffset = 0
+		}
+		s[t].Columns[c].SlotOffset = slot
+		s[t].Columns[c].ByteOffset = byteOffset
+		s[t].Columns[c].BitOffset = -1
+		byteOffset += uint8(size)
+		if byteOffset >= 32 {
+			slot++
+			byteOffset = 0
+		}
+	}
+
+	for c := range s[t].Columns {
+		major, _ := ast.DecomposeDataType(s[t].Columns[c].Type)
+		if major == ast.DataTypeMajorBool {
+			bools = append(bools, c)
+			continue
+		}
+		size := int(s[t].Columns[c].Type.Size())
+		if size > 32 {
+			size = 32
+		}
+		place(c, size)
+	}
+
+	// Pack every bool column of this table into a trailing bitmap,
+	// 8 bits per byte, continuing from wherever the scalar stream left
+	// off rather than starting a fresh slot.
+	for i, c := range bools {
+		bit := i % 8
+		if bit == 0 {
+			if uint64(byteOffset)+1 > 32 {
+				slot++
+				byteOffset = 0
+			}
+		}
+		s[t].Columns[c].SlotOffset = slot
+		s[t].Columns[c].ByteOffset = byteOffset
+		s[t].Columns[c].BitOffset = int8(bit)
+		if bit == 7 || i == len(bools)-1 {
+			byteOffset++
+			if byteOffset >= 32 {
+				slot++
+				byteOffset = 0
+			}
+		}
+	}
+}